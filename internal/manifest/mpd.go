@@ -0,0 +1,208 @@
+// Package manifest builds a DASH MPD manifest out of a Brightcove video's
+// renditions, so a companion publication event can be emitted alongside the
+// usual Content payload without a separate packager pass.
+package manifest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/adambraimbridge/video-mapper/internal/mp4"
+)
+
+// Rendition is a single playable source of a Brightcove video, as found in its
+// "sources" array.
+type Rendition struct {
+	URL           string
+	Container     string // e.g. "MP4", "M3U8"
+	EncodingRate  int    // bits per second
+	Width, Height int
+}
+
+// segmented reports whether r is an adaptively-segmented source (HLS) rather
+// than a single progressive file, which decides whether Build addresses it
+// with a SegmentTemplate or a plain BaseURL.
+func (r Rendition) segmented() bool {
+	return r.Container == "M3U8"
+}
+
+// RenditionsFromBrightcove extracts the playable renditions from a decoded
+// Brightcove video JSON: progressive MP4 files (addressed by BaseURL) and HLS
+// playlists (addressed by SegmentTemplate). Any other container (e.g. a raw
+// WebM source) is skipped since this package doesn't know how to address it.
+func RenditionsFromBrightcove(brightcoveVideo map[string]interface{}) []Rendition {
+	rawSources, _ := brightcoveVideo["sources"].([]interface{})
+	var renditions []Rendition
+	for _, raw := range rawSources {
+		source, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		container, _ := source["container"].(string)
+		if container != "MP4" && container != "M3U8" {
+			continue
+		}
+		src, _ := source["src"].(string)
+		if src == "" {
+			continue
+		}
+		rate, _ := source["encoding_rate"].(float64)
+		width, _ := source["width"].(float64)
+		height, _ := source["height"].(float64)
+		renditions = append(renditions, Rendition{
+			URL:          src,
+			Container:    container,
+			EncodingRate: int(rate),
+			Width:        int(width),
+			Height:       int(height),
+		})
+	}
+	return renditions
+}
+
+// mpd mirrors the small subset of the DASH-IF MPD schema this package emits:
+// a single static Period containing one AdaptationSet per media type, each
+// holding one Representation per rendition.
+type mpd struct {
+	XMLName                   xml.Name `xml:"MPD"`
+	Xmlns                     string   `xml:"xmlns,attr"`
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	Period                    period   `xml:"Period"`
+}
+
+type period struct {
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	MimeType        string           `xml:"mimeType,attr"`
+	Representations []representation `xml:"Representation"`
+}
+
+type representation struct {
+	ID              string           `xml:"id,attr"`
+	Bandwidth       int              `xml:"bandwidth,attr"`
+	Codecs          string           `xml:"codecs,attr,omitempty"`
+	Width           int              `xml:"width,attr,omitempty"`
+	Height          int              `xml:"height,attr,omitempty"`
+	BaseURL         string           `xml:"BaseURL,omitempty"`
+	SegmentTemplate *segmentTemplate `xml:"SegmentTemplate,omitempty"`
+}
+
+// segmentTemplate addresses an HLS rendition: media points straight at its
+// .m3u8 playlist, since this package doesn't repackage HLS segments into
+// DASH's own numbered/timed addressing schemes.
+type segmentTemplate struct {
+	Media string `xml:"media,attr"`
+}
+
+// MPDBuilder builds a static DASH MPD manifest from a Brightcove video's
+// renditions and the MediaInfo recovered by the mp4 prober.
+type MPDBuilder struct{}
+
+// NewMPDBuilder returns an MPDBuilder.
+func NewMPDBuilder() *MPDBuilder {
+	return &MPDBuilder{}
+}
+
+// Build assembles a static MPD from renditions, using info (which may be nil
+// if media probing was disabled or failed) for duration and codec detail.
+func (b *MPDBuilder) Build(uuid string, renditions []Rendition, info *mp4.MediaInfo) ([]byte, error) {
+	if len(renditions) == 0 {
+		return nil, fmt.Errorf("manifest: no playable (MP4 or M3U8) renditions to build an MPD for video [%v]", uuid)
+	}
+
+	var videoReps, audioReps []representation
+	for i, r := range renditions {
+		isVideo := r.Height > 0
+		rep := representation{
+			ID:        fmt.Sprintf("%v-%d", uuid, i),
+			Bandwidth: r.EncodingRate,
+			Codecs:    codecFor(info, isVideo),
+			Width:     r.Width,
+			Height:    r.Height,
+		}
+		if r.segmented() {
+			rep.SegmentTemplate = &segmentTemplate{Media: r.URL}
+		} else {
+			rep.BaseURL = r.URL
+		}
+		if isVideo {
+			videoReps = append(videoReps, rep)
+		} else {
+			audioReps = append(audioReps, rep)
+		}
+	}
+
+	var adaptationSets []adaptationSet
+	if len(videoReps) > 0 {
+		adaptationSets = append(adaptationSets, adaptationSet{MimeType: "video/mp4", Representations: videoReps})
+	}
+	if len(audioReps) > 0 {
+		adaptationSets = append(adaptationSets, adaptationSet{MimeType: "audio/mp4", Representations: audioReps})
+	}
+
+	doc := mpd{
+		Xmlns:                     "urn:mpeg:dash:schema:mpd:2011",
+		Type:                      "static",
+		MediaPresentationDuration: isoDuration(info),
+		Period:                    period{AdaptationSets: adaptationSets},
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// codecFor returns the FourCC of the probed track matching wantVideo (a video
+// track if true, an audio track otherwise), or "" if probing wasn't done or
+// didn't recover a track of that type. info describes only the single
+// highest-quality rendition that was probed, so every rendition of the same
+// type (video or audio) is given that rendition's codec, rather than trying
+// to match it up by position against renditions that were never probed.
+func codecFor(info *mp4.MediaInfo, wantVideo bool) string {
+	if info == nil {
+		return ""
+	}
+	wantType := "audio"
+	if wantVideo {
+		wantType = "video"
+	}
+	for _, t := range info.Tracks {
+		if t.Type == wantType {
+			return t.Codec
+		}
+	}
+	return ""
+}
+
+// isoDuration renders info's duration as an ISO-8601 "PTxHxMxS" duration, or
+// "PT0S" if it's unknown.
+func isoDuration(info *mp4.MediaInfo) string {
+	if info == nil || info.DurationMs == 0 {
+		return "PT0S"
+	}
+	remainingMs := info.DurationMs
+	hours := remainingMs / 3600000
+	remainingMs -= hours * 3600000
+	minutes := remainingMs / 60000
+	remainingMs -= minutes * 60000
+	seconds := float64(remainingMs) / 1000
+
+	var b strings.Builder
+	b.WriteString("PT")
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	if seconds > 0 || (hours == 0 && minutes == 0) {
+		fmt.Fprintf(&b, "%gS", seconds)
+	}
+	return b.String()
+}
@@ -0,0 +1,276 @@
+// Package mp4 probes a small byte range of an MP4/MOV rendition to recover
+// real media metadata (brand, duration, tracks, dimensions, audio sample rate)
+// instead of guessing it from a file extension.
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// probeRangeBytes is how much of the file we ranged-GET. It's enough to cover
+// the ftyp and moov boxes for almost every rendition; mdat (the actual media
+// data) comes after and is never fetched.
+const probeRangeBytes = 2 * 1024 * 1024
+
+// Track describes a single track found in the moov atom.
+type Track struct {
+	Type       string `json:"type"` // "video" or "audio"
+	Codec      string `json:"codec"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	SampleRate int    `json:"sampleRate,omitempty"`
+}
+
+// MediaInfo is the metadata recovered from probing a rendition's MP4 boxes.
+type MediaInfo struct {
+	MajorBrand string  `json:"majorBrand"`
+	DurationMs int64   `json:"durationMs"`
+	Tracks     []Track `json:"tracks"`
+}
+
+// MediaType maps the probed major brand to a mime type, falling back to video/mp4
+// for brands we don't special-case.
+func (m MediaInfo) MediaType() string {
+	switch m.MajorBrand {
+	case "qt  ":
+		return "video/quicktime"
+	default:
+		return "video/mp4"
+	}
+}
+
+// Prober fetches the head of a rendition and parses its MP4 boxes, caching
+// results by a caller-supplied key (typically Brightcove id+updated_at) so a
+// republish of unchanged media doesn't get re-probed.
+type Prober struct {
+	client  *http.Client
+	timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[string]MediaInfo
+}
+
+// NewProber returns a Prober that gives up on a single probe after timeout.
+func NewProber(timeout time.Duration) *Prober {
+	return &Prober{
+		client:  &http.Client{Timeout: timeout},
+		timeout: timeout,
+		cache:   make(map[string]MediaInfo),
+	}
+}
+
+// Probe ranged-GETs the start of url and parses its MP4 boxes. Results are
+// cached by cacheKey; callers should fall back to extension-based detection
+// on error rather than failing the whole mapping.
+func (p *Prober) Probe(url, cacheKey string) (MediaInfo, error) {
+	p.mu.Lock()
+	if cached, found := p.cache[cacheKey]; found {
+		p.mu.Unlock()
+		return cached, nil
+	}
+	p.mu.Unlock()
+
+	body, err := p.fetchRange(url)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+	info, err := parseBoxes(body)
+	if err != nil {
+		return MediaInfo{}, err
+	}
+
+	p.mu.Lock()
+	p.cache[cacheKey] = info
+	p.mu.Unlock()
+	return info, nil
+}
+
+func (p *Prober) fetchRange(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", probeRangeBytes-1))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status probing %v: %v", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// box is a single top-level or nested MP4 atom: a 4-byte size, a 4-byte type,
+// and its payload (which for container boxes is itself a sequence of boxes).
+type box struct {
+	typ     string
+	payload []byte
+}
+
+func readBoxes(data []byte) []box {
+	var boxes []box
+	for len(data) >= 8 {
+		size := binary.BigEndian.Uint32(data[0:4])
+		typ := string(data[4:8])
+		if size < 8 || uint64(size) > uint64(len(data)) {
+			break
+		}
+		boxes = append(boxes, box{typ: typ, payload: data[8:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+var containerBoxTypes = map[string]bool{
+	"moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+}
+
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+func parseBoxes(data []byte) (MediaInfo, error) {
+	top := readBoxes(data)
+
+	ftyp, found := findBox(top, "ftyp")
+	if !found {
+		return MediaInfo{}, errors.New("mp4: no ftyp box found in probed range")
+	}
+	if len(ftyp.payload) < 4 {
+		return MediaInfo{}, errors.New("mp4: truncated ftyp box")
+	}
+	majorBrand := string(ftyp.payload[0:4])
+
+	moov, found := findBox(top, "moov")
+	if !found {
+		return MediaInfo{}, errors.New("mp4: no moov box found in probed range; try a larger probe range")
+	}
+	moovChildren := readBoxes(moov.payload)
+
+	var durationMs int64
+	if mvhd, found := findBox(moovChildren, "mvhd"); found {
+		durationMs = parseMvhdDuration(mvhd.payload)
+	}
+
+	var tracks []Track
+	for _, child := range moovChildren {
+		if child.typ != "trak" {
+			continue
+		}
+		if t, ok := parseTrak(child.payload); ok {
+			tracks = append(tracks, t)
+		}
+	}
+
+	return MediaInfo{
+		MajorBrand: majorBrand,
+		DurationMs: durationMs,
+		Tracks:     tracks,
+	}, nil
+}
+
+// parseMvhdDuration reads the version-0 form of mvhd (creation/modification time,
+// timescale and duration as 32-bit fields); version 1 (64-bit fields) isn't
+// needed for the renditions this mapper sees and is treated as "unknown".
+func parseMvhdDuration(payload []byte) int64 {
+	if len(payload) < 1 || payload[0] != 0 {
+		return 0
+	}
+	if len(payload) < 20 {
+		return 0
+	}
+	timescale := binary.BigEndian.Uint32(payload[12:16])
+	duration := binary.BigEndian.Uint32(payload[16:20])
+	if timescale == 0 {
+		return 0
+	}
+	return int64(duration) * 1000 / int64(timescale)
+}
+
+func parseTrak(payload []byte) (Track, bool) {
+	trakChildren := readBoxes(payload)
+
+	var width, height int
+	if tkhd, found := findBox(trakChildren, "tkhd"); found {
+		width, height = parseTkhdDimensions(tkhd.payload)
+	}
+
+	mdia, found := findBox(trakChildren, "mdia")
+	if !found {
+		return Track{}, false
+	}
+	mdiaChildren := readBoxes(mdia.payload)
+	minf, found := findBox(mdiaChildren, "minf")
+	if !found {
+		return Track{}, false
+	}
+	minfChildren := readBoxes(minf.payload)
+	stbl, found := findBox(minfChildren, "stbl")
+	if !found {
+		return Track{}, false
+	}
+	stblChildren := readBoxes(stbl.payload)
+	stsd, found := findBox(stblChildren, "stsd")
+	if !found || len(stsd.payload) < 8 {
+		return Track{}, false
+	}
+	// stsd: version/flags (4 bytes), entry count (4 bytes), then sample entries;
+	// the first sample entry's type is the codec FourCC.
+	sampleEntries := readBoxes(stsd.payload[8:])
+	if len(sampleEntries) == 0 {
+		return Track{}, false
+	}
+	codec := sampleEntries[0].typ
+
+	trackType := "video"
+	var sampleRate int
+	switch codec {
+	case "mp4a":
+		trackType = "audio"
+		sampleRate = parseAudioSampleRate(sampleEntries[0].payload)
+	case "avc1", "hvc1", "hev1":
+		trackType = "video"
+	}
+
+	return Track{
+		Type:       trackType,
+		Codec:      codec,
+		Width:      width,
+		Height:     height,
+		SampleRate: sampleRate,
+	}, true
+}
+
+// parseTkhdDimensions reads the version-0 form of tkhd; width/height are stored
+// as 16.16 fixed point in the last 8 bytes of the box.
+func parseTkhdDimensions(payload []byte) (int, int) {
+	if len(payload) < 1 || payload[0] != 0 || len(payload) < 84 {
+		return 0, 0
+	}
+	width := binary.BigEndian.Uint32(payload[76:80]) >> 16
+	height := binary.BigEndian.Uint32(payload[80:84]) >> 16
+	return int(width), int(height)
+}
+
+// parseAudioSampleRate reads the sample rate out of an mp4a audio sample entry,
+// which is stored in the last 4 bytes (offset 24) of the 28-byte fixed-size
+// audio entry fields, as a 16.16 fixed point value.
+func parseAudioSampleRate(payload []byte) int {
+	if len(payload) < 28 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(payload[24:28]) >> 16)
+}
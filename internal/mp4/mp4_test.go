@@ -0,0 +1,156 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// makeBox encodes a single MP4 atom: a 4-byte size, a 4-byte type and its
+// payload. Named makeBox, not box, to avoid colliding with the unexported
+// box struct this package already declares.
+func makeBox(typ string, payload []byte) []byte {
+	b := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)))
+	copy(b[4:8], typ)
+	copy(b[8:], payload)
+	return b
+}
+
+func TestReadBoxes(t *testing.T) {
+	data := append(makeBox("ftyp", []byte("isom")), makeBox("free", []byte{1, 2, 3})...)
+	boxes := readBoxes(data)
+	if len(boxes) != 2 {
+		t.Fatalf("readBoxes() returned %d boxes, want 2", len(boxes))
+	}
+	if boxes[0].typ != "ftyp" || string(boxes[0].payload) != "isom" {
+		t.Errorf("boxes[0] = %+v, want ftyp/isom", boxes[0])
+	}
+	if boxes[1].typ != "free" {
+		t.Errorf("boxes[1].typ = %q, want free", boxes[1].typ)
+	}
+}
+
+func TestReadBoxesStopsOnTruncatedSize(t *testing.T) {
+	data := makeBox("ftyp", []byte("isom"))
+	data = append(data, 0, 0, 0, 255, 'f', 'r', 'e', 'e') // claims a size longer than the data that follows
+	boxes := readBoxes(data)
+	if len(boxes) != 1 {
+		t.Fatalf("readBoxes() returned %d boxes, want 1 (truncated trailing box dropped)", len(boxes))
+	}
+}
+
+func TestParseMvhdDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		wantMs  int64
+	}{
+		{name: "too short is unknown", payload: []byte{0, 0, 0}, wantMs: 0},
+		{name: "version 1 (64-bit fields) is treated as unknown", payload: append([]byte{1}, make([]byte, 19)...), wantMs: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMvhdDuration(tt.payload); got != tt.wantMs {
+				t.Errorf("parseMvhdDuration() = %d, want %d", got, tt.wantMs)
+			}
+		})
+	}
+
+	payload := make([]byte, 20)
+	binary.BigEndian.PutUint32(payload[12:16], 1000) // timescale
+	binary.BigEndian.PutUint32(payload[16:20], 5500) // duration
+	if got := parseMvhdDuration(payload); got != 5500 {
+		t.Errorf("parseMvhdDuration() = %d, want 5500", got)
+	}
+}
+
+func TestParseTkhdDimensions(t *testing.T) {
+	payload := make([]byte, 84)
+	binary.BigEndian.PutUint32(payload[76:80], 640<<16)
+	binary.BigEndian.PutUint32(payload[80:84], 360<<16)
+	width, height := parseTkhdDimensions(payload)
+	if width != 640 || height != 360 {
+		t.Errorf("parseTkhdDimensions() = (%d, %d), want (640, 360)", width, height)
+	}
+
+	if w, h := parseTkhdDimensions(make([]byte, 10)); w != 0 || h != 0 {
+		t.Errorf("parseTkhdDimensions(too short) = (%d, %d), want (0, 0)", w, h)
+	}
+}
+
+func TestParseAudioSampleRate(t *testing.T) {
+	payload := make([]byte, 28)
+	binary.BigEndian.PutUint32(payload[24:28], 44100<<16)
+	if got := parseAudioSampleRate(payload); got != 44100 {
+		t.Errorf("parseAudioSampleRate() = %d, want 44100", got)
+	}
+	if got := parseAudioSampleRate(make([]byte, 10)); got != 0 {
+		t.Errorf("parseAudioSampleRate(too short) = %d, want 0", got)
+	}
+}
+
+// buildMinimalMP4 assembles an ftyp + moov box tree with one video (avc1) and
+// one audio (mp4a) trak, enough for parseBoxes to recover both tracks plus
+// the overall duration.
+func buildMinimalMP4() []byte {
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:80], 1280<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 720<<16)
+
+	videoStsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, makeBox("avc1", nil)...)
+	videoTrak := makeBox("trak", append(makeBox("tkhd", tkhd), makeBox("mdia", makeBox("minf", makeBox("stbl", makeBox("stsd", videoStsd))))...))
+
+	audioSampleEntry := make([]byte, 28)
+	binary.BigEndian.PutUint32(audioSampleEntry[24:28], 44100<<16)
+	audioStsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, makeBox("mp4a", audioSampleEntry)...)
+	audioTrak := makeBox("trak", makeBox("mdia", makeBox("minf", makeBox("stbl", makeBox("stsd", audioStsd)))))
+
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 12000)
+
+	moov := makeBox("moov", append(makeBox("mvhd", mvhd), append(videoTrak, audioTrak...)...))
+	ftyp := makeBox("ftyp", []byte("isom"))
+	return append(ftyp, moov...)
+}
+
+func TestParseBoxesIntegration(t *testing.T) {
+	info, err := parseBoxes(buildMinimalMP4())
+	if err != nil {
+		t.Fatalf("parseBoxes() error = %v", err)
+	}
+	if info.MajorBrand != "isom" {
+		t.Errorf("MajorBrand = %q, want isom", info.MajorBrand)
+	}
+	if info.DurationMs != 12000 {
+		t.Errorf("DurationMs = %d, want 12000", info.DurationMs)
+	}
+	if len(info.Tracks) != 2 {
+		t.Fatalf("len(Tracks) = %d, want 2", len(info.Tracks))
+	}
+	video, audio := info.Tracks[0], info.Tracks[1]
+	if video.Type != "video" || video.Codec != "avc1" || video.Width != 1280 || video.Height != 720 {
+		t.Errorf("video track = %+v, want type=video codec=avc1 1280x720", video)
+	}
+	if audio.Type != "audio" || audio.Codec != "mp4a" || audio.SampleRate != 44100 {
+		t.Errorf("audio track = %+v, want type=audio codec=mp4a sampleRate=44100", audio)
+	}
+}
+
+func TestParseBoxesMissingFtypOrMoov(t *testing.T) {
+	if _, err := parseBoxes(makeBox("moov", nil)); err == nil {
+		t.Error("parseBoxes() with no ftyp box: want error, got nil")
+	}
+	if _, err := parseBoxes(makeBox("ftyp", []byte("isom"))); err == nil {
+		t.Error("parseBoxes() with no moov box: want error, got nil")
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	if got := (MediaInfo{MajorBrand: "qt  "}).MediaType(); got != "video/quicktime" {
+		t.Errorf("MediaType() for qt brand = %q, want video/quicktime", got)
+	}
+	if got := (MediaInfo{MajorBrand: "isom"}).MediaType(); got != "video/mp4" {
+		t.Errorf("MediaType() for isom brand = %q, want video/mp4", got)
+	}
+}
@@ -0,0 +1,59 @@
+package listeners
+
+import "context"
+
+// Dispatcher fans a mapped event out to every registered EventListener.
+type Dispatcher struct {
+	listeners   []EventListener
+	haltOnError bool
+}
+
+// NewDispatcher returns a Dispatcher over ls. When haltOnError is true, the
+// first listener error is returned immediately without notifying the
+// remaining listeners, so the caller can treat it as fatal (mirroring the
+// rest of the app's "log setup failures and panic" pattern) and the Kafka
+// offset is never committed. When false, every listener still runs and only
+// the last error is returned, for logging.
+func NewDispatcher(haltOnError bool, ls ...EventListener) *Dispatcher {
+	return &Dispatcher{listeners: ls, haltOnError: haltOnError}
+}
+
+// HaltOnError reports whether the Dispatcher was configured to treat a
+// listener error as fatal. Callers use this to decide whether an error
+// returned by DispatchMappedEvent or Commit should be logged and skipped or
+// should stop the app.
+func (d *Dispatcher) HaltOnError() bool {
+	return d.haltOnError
+}
+
+// DispatchMappedEvent calls OnMappedEvent on every registered listener.
+func (d *Dispatcher) DispatchMappedEvent(ctx context.Context, event Event, headers map[string]string) error {
+	var lastErr error
+	for _, l := range d.listeners {
+		if err := l.OnMappedEvent(ctx, event, headers); err != nil {
+			if d.haltOnError {
+				return err
+			}
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Commit calls OnCommit on every registered listener. The consumer this
+// mapper uses has no concept of batching its own offset commits: it invokes
+// its message callback, and so this Commit, once per message. Listeners that
+// need to flush state (e.g. FileListener) should do so accordingly rather
+// than assuming a larger unit of work.
+func (d *Dispatcher) Commit(ctx context.Context) error {
+	var lastErr error
+	for _, l := range d.listeners {
+		if err := l.OnCommit(ctx); err != nil {
+			if d.haltOnError {
+				return err
+			}
+			lastErr = err
+		}
+	}
+	return lastErr
+}
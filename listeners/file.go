@@ -0,0 +1,94 @@
+package listeners
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// frame is what FileListener actually writes: the event plus enough of its
+// originating message's coordinates to let a replay tool pick up where it left off.
+type frame struct {
+	Partition string `json:"partition"`
+	Offset    string `json:"offset"`
+	Event     Event  `json:"event"`
+}
+
+// FileListener writes every mapped event as a length-prefixed JSON frame to a
+// file under baseDir, for audit and replay. It keeps a single open file, for
+// the partition it last wrote, rotating to a new one whenever the partition
+// changes.
+type FileListener struct {
+	baseDir string
+
+	mu               sync.Mutex
+	currentPartition string
+	currentFile      *os.File
+}
+
+// NewFileListener returns a FileListener that writes rotating frame files under baseDir.
+func NewFileListener(baseDir string) *FileListener {
+	return &FileListener{baseDir: baseDir}
+}
+
+func (f *FileListener) OnMappedEvent(ctx context.Context, event Event, headers map[string]string) error {
+	partition := headers["Partition"]
+	if partition == "" {
+		partition = "0"
+	}
+	offset := headers["Offset"]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := f.fileForPartitionLocked(partition, offset)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(frame{Partition: partition, Offset: offset, Event: event})
+	if err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(body)))
+	if _, err := file.Write(length[:]); err != nil {
+		return err
+	}
+	_, err = file.Write(body)
+	return err
+}
+
+// fileForPartitionLocked returns the currently open file for partition, rotating
+// to a new one (named after both partition and the offset it starts from) if
+// the partition has changed since the last call.
+func (f *FileListener) fileForPartitionLocked(partition, offset string) (*os.File, error) {
+	if f.currentFile != nil && f.currentPartition == partition {
+		return f.currentFile, nil
+	}
+	if f.currentFile != nil {
+		f.currentFile.Close()
+	}
+	fileName := filepath.Join(f.baseDir, fmt.Sprintf("partition-%s_offset-%s.frames", partition, offset))
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.currentPartition = partition
+	f.currentFile = file
+	return file, nil
+}
+
+// OnCommit flushes the currently open file so committed offsets and the frames
+// written for them stay consistent on disk.
+func (f *FileListener) OnCommit(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.currentFile == nil {
+		return nil
+	}
+	return f.currentFile.Sync()
+}
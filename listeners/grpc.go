@@ -0,0 +1,30 @@
+package listeners
+
+import "context"
+
+// EventStreamClient is the subset of a generated gRPC client this listener
+// needs: something that can stream one Event at a time to an external
+// subscriber. Callers construct the real client from their .proto-generated
+// package and wrap it here.
+type EventStreamClient interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// GRPCListener streams every mapped event to an external subscriber over gRPC.
+type GRPCListener struct {
+	client EventStreamClient
+}
+
+// NewGRPCListener wraps an EventStreamClient as an EventListener.
+func NewGRPCListener(client EventStreamClient) *GRPCListener {
+	return &GRPCListener{client: client}
+}
+
+func (g *GRPCListener) OnMappedEvent(ctx context.Context, event Event, headers map[string]string) error {
+	return g.client.Send(ctx, event)
+}
+
+// OnCommit is a no-op: each event is already sent as soon as it's mapped.
+func (g *GRPCListener) OnCommit(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package listeners
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Financial-Times/message-queue-go-producer/producer"
+)
+
+// KafkaListener is the built-in EventListener that replaces the mapper's
+// previous single hard-wired producer.MessageProducer.
+type KafkaListener struct {
+	producer producer.MessageProducer
+}
+
+// NewKafkaListener wraps an already-configured MessageProducer as an EventListener.
+func NewKafkaListener(p producer.MessageProducer) *KafkaListener {
+	return &KafkaListener{producer: p}
+}
+
+func (k *KafkaListener) OnMappedEvent(ctx context.Context, event Event, headers map[string]string) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	outHeaders := make(map[string]string, len(headers)+1)
+	for name, value := range headers {
+		outHeaders[name] = value
+	}
+	if event.MediaType != "" {
+		outHeaders["Content-Type"] = event.MediaType
+	}
+	return k.producer.SendMessage("", producer.Message{Headers: outHeaders, Body: string(body)})
+}
+
+// OnCommit is a no-op: the producer has nothing to flush between batches.
+func (k *KafkaListener) OnCommit(ctx context.Context) error {
+	return nil
+}
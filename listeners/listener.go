@@ -0,0 +1,30 @@
+// Package listeners decouples videoMapper from any single downstream sink.
+// A mapped message fans out to every registered EventListener instead of
+// going straight to a Kafka producer, so the mapper can be reused in
+// non-Kafka deployments (file-based audit trails, gRPC subscribers, ...).
+package listeners
+
+import "context"
+
+// Event is the publication event dispatched to every listener: a Content URI,
+// its payload (JSON or, for a DASH manifest, XML), the media type of that
+// payload, and the lastModified timestamp it was published with.
+type Event struct {
+	ContentUri   string `json:"contentUri"`
+	Payload      string `json:"payload"`
+	MediaType    string `json:"mediaType"`
+	LastModified string `json:"lastModified"`
+}
+
+// EventListener receives every mapped event and is given a chance to flush
+// once the message that produced it has been fully handled.
+type EventListener interface {
+	// OnMappedEvent is called once per mapped event, with the original message
+	// headers alongside it (so a listener can still key off Origin-System-Id,
+	// X-Request-Id, etc).
+	OnMappedEvent(ctx context.Context, event Event, headers map[string]string) error
+	// OnCommit is called once per consumed message that was mapped, after all
+	// of that message's mapped events have been dispatched. A message with an
+	// unknown origin or one that fails to map gets no OnCommit call.
+	OnCommit(ctx context.Context) error
+}
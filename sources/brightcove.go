@@ -0,0 +1,273 @@
+package sources
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/adambraimbridge/video-mapper/internal/mp4"
+)
+
+const brightcoveOrigin = "http://cmdb.ft.com/systems/brightcove"
+const brightcoveAuthority = "http://api.ft.com/system/BRIGHTCOVE"
+const videoMediaTypeBase = "video/"
+const audioMediaTypeBase = "audio/"
+
+// defaultVideoExtension and defaultAudioExtension are used in place of a
+// MediaType's extension when neither a codec nor a fileName extension could
+// be determined, so the result is always a real mime type (e.g. "video/mp4")
+// rather than a bare "video/" or "audio/" with nothing after the slash.
+const defaultVideoExtension = "mp4"
+const defaultAudioExtension = "mp3"
+
+// SplitPolicy controls when Brightcove.MapTracks emits separate video and
+// audio payloads instead of a single one.
+type SplitPolicy string
+
+const (
+	// SplitNever always emits a single payload, even for audio-only assets
+	// (whose MediaType is still derived correctly as audio/<codec>).
+	SplitNever SplitPolicy = "never"
+	// SplitAuto splits only when both a video and an audio track were found
+	// with a known codec, so a partial/ambiguous probe doesn't produce a
+	// half-guessed second payload.
+	SplitAuto SplitPolicy = "auto"
+	// SplitAlways splits whenever both a video and an audio track are
+	// detected at all, even if one codec couldn't be determined.
+	SplitAlways SplitPolicy = "always"
+)
+
+// Brightcove maps native video JSON produced by Brightcove's CMS API.
+type Brightcove struct {
+	// prober is nil unless media probing (--probe-media) is enabled, in which
+	// case Map uses it to enrich the payload with real MP4 box metadata,
+	// falling back silently to extension-based detection on probe failure.
+	prober      *mp4.Prober
+	splitPolicy SplitPolicy
+}
+
+// NewBrightcove returns a VideoSource for the Brightcove origin. prober may be
+// nil, in which case track composition and MediaType are derived from
+// Brightcove's own JSON fields instead of probed MP4 boxes.
+func NewBrightcove(prober *mp4.Prober, splitPolicy SplitPolicy) *Brightcove {
+	return &Brightcove{prober: prober, splitPolicy: splitPolicy}
+}
+
+func (b *Brightcove) Origin() string    { return brightcoveOrigin }
+func (b *Brightcove) Authority() string { return brightcoveAuthority }
+func (b *Brightcove) Name() string      { return "brightcove" }
+
+// Map returns the primary payload for brightcoveVideo. When MapTracks would
+// split it into separate video and audio payloads, this returns the first of
+// them; callers that need both should use MapTracks directly.
+func (b *Brightcove) Map(brightcoveVideo map[string]interface{}, publishReference, lastModified string) (Payload, error) {
+	payloads, err := b.MapTracks(brightcoveVideo, publishReference, lastModified)
+	if err != nil {
+		return Payload{}, err
+	}
+	return payloads[0], nil
+}
+
+// MapTracks maps brightcoveVideo to one payload, or two when its video and
+// audio tracks are independently publishable and splitPolicy calls for it. A
+// two-payload result shares the same UUID but carries distinct identifiers
+// (<id>-video, <id>-audio); callers are expected to give each its own
+// contentUri suffix based on its MediaType.
+func (b *Brightcove) MapTracks(brightcoveVideo map[string]interface{}, publishReference, lastModified string) ([]Payload, error) {
+	uuid, _ := brightcoveVideo["uuid"].(string)
+	if uuid == "" {
+		return nil, fmt.Errorf("uuid field of native brightcove video JSON is null. Skipping message.")
+	}
+	id, _ := brightcoveVideo["id"].(string)
+	if id == "" {
+		return nil, fmt.Errorf("id field of native brightcove video JSON is null. Skipping message.")
+	}
+	publishedDate, _ := brightcoveVideo["updated_at"].(string)
+	if publishedDate == "" {
+		return nil, fmt.Errorf("updated_at field of native brightcove video JSON is null. Skipping message.")
+	}
+	fileName, _ := brightcoveVideo["name"].(string)
+	if fileName == "" {
+		log.Printf("WARN filename field of native brightcove video JSON is null, falling back to a default MediaType.")
+	}
+
+	var info *mp4.MediaInfo
+	if b.prober != nil {
+		if renditionURL, found := highestQualityRenditionURL(brightcoveVideo); found {
+			cacheKey := id + "+" + publishedDate
+			if probed, err := b.prober.Probe(renditionURL, cacheKey); err == nil {
+				info = &probed
+			}
+			// Probe failures (network, timeout, unrecognised boxes) are expected for
+			// some renditions; fall back silently to the JSON/extension-based
+			// detection below.
+		}
+	}
+
+	composition := detectComposition(brightcoveVideo, info, fileName)
+
+	if shouldSplit(b.splitPolicy, composition) {
+		videoPayload := Payload{
+			UUID:             uuid,
+			Identifiers:      []Identifier{{Authority: brightcoveAuthority, IdentifierValue: id + "-video"}},
+			PublishedDate:    publishedDate,
+			MediaType:        videoMediaTypeBase + composition.videoCodec,
+			PublishReference: publishReference,
+			LastModified:     lastModified,
+			MediaInfo:        info,
+		}
+		audioPayload := Payload{
+			UUID:             uuid,
+			Identifiers:      []Identifier{{Authority: brightcoveAuthority, IdentifierValue: id + "-audio"}},
+			PublishedDate:    publishedDate,
+			MediaType:        audioMediaTypeBase + composition.audioCodec,
+			PublishReference: publishReference,
+			LastModified:     lastModified,
+			MediaInfo:        info,
+		}
+		return []Payload{videoPayload, audioPayload}, nil
+	}
+
+	extension := strings.TrimPrefix(filepath.Ext(fileName), ".")
+	mediaType := videoMediaTypeBase + firstNonEmpty(extension, defaultVideoExtension)
+	switch {
+	case composition.hasAudio && !composition.hasVideo:
+		mediaType = audioMediaTypeBase + firstNonEmpty(composition.audioCodec, extension, defaultAudioExtension)
+	case composition.hasVideo && composition.videoCodec != "":
+		mediaType = videoMediaTypeBase + composition.videoCodec
+	case composition.hasVideo && info != nil:
+		// No codec was recovered for the video track, but we do have the
+		// probed major brand: use it rather than guessing from extension.
+		mediaType = info.MediaType()
+	case composition.hasVideo:
+		mediaType = videoMediaTypeBase + firstNonEmpty(extension, defaultVideoExtension)
+	}
+
+	p := Payload{
+		UUID:             uuid,
+		Identifiers:      []Identifier{{Authority: brightcoveAuthority, IdentifierValue: id}},
+		PublishedDate:    publishedDate,
+		MediaType:        mediaType,
+		PublishReference: publishReference,
+		LastModified:     lastModified,
+		MediaInfo:        info,
+	}
+	return []Payload{p}, nil
+}
+
+// trackComposition summarises which kinds of track a Brightcove asset has,
+// and their codecs where known.
+type trackComposition struct {
+	hasVideo, hasAudio     bool
+	videoCodec, audioCodec string
+}
+
+// shouldSplit decides whether MapTracks should emit c as two payloads rather
+// than one, given policy.
+func shouldSplit(policy SplitPolicy, c trackComposition) bool {
+	switch policy {
+	case SplitAlways:
+		return c.hasVideo && c.hasAudio
+	case SplitAuto:
+		return c.hasVideo && c.hasAudio && c.videoCodec != "" && c.audioCodec != ""
+	default:
+		return false
+	}
+}
+
+// detectComposition figures out track composition from, in order of
+// preference: the mp4 probe (if one was done), Brightcove's own "type" field
+// (with the codec taken from the container of its first rendition), and
+// finally the extension of fileName. Without a "type" field the rendition
+// containers are not consulted.
+func detectComposition(brightcoveVideo map[string]interface{}, info *mp4.MediaInfo, fileName string) trackComposition {
+	var c trackComposition
+
+	if info != nil {
+		for _, t := range info.Tracks {
+			switch t.Type {
+			case "video":
+				c.hasVideo = true
+				c.videoCodec = t.Codec
+			case "audio":
+				c.hasAudio = true
+				c.audioCodec = t.Codec
+			}
+		}
+		if c.hasVideo || c.hasAudio {
+			return c
+		}
+	}
+
+	switch strings.ToUpper(fmt.Sprintf("%v", brightcoveVideo["type"])) {
+	case "AUDIO":
+		c.hasAudio = true
+		c.audioCodec = renditionContainer(brightcoveVideo)
+		return c
+	case "VIDEO":
+		c.hasVideo = true
+		c.videoCodec = renditionContainer(brightcoveVideo)
+		return c
+	}
+
+	extension := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	switch extension {
+	case "mp3", "m4a":
+		c.hasAudio = true
+		c.audioCodec = extension
+	default:
+		c.hasVideo = true
+		c.videoCodec = extension
+	}
+	return c
+}
+
+// renditionContainer returns the lower-cased container of the first rendition
+// with one set, e.g. "mp4" or "mp3".
+func renditionContainer(brightcoveVideo map[string]interface{}) string {
+	rawSources, _ := brightcoveVideo["sources"].([]interface{})
+	for _, raw := range rawSources {
+		source, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if container, _ := source["container"].(string); container != "" {
+			return strings.ToLower(container)
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// highestQualityRenditionURL picks the rendition with the largest encoding_rate
+// from Brightcove's "sources" array, which is the one worth probing.
+func highestQualityRenditionURL(brightcoveVideo map[string]interface{}) (string, bool) {
+	rawSources, _ := brightcoveVideo["sources"].([]interface{})
+	var bestURL string
+	var bestRate float64 = -1
+	for _, raw := range rawSources {
+		source, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		src, _ := source["src"].(string)
+		if src == "" {
+			continue
+		}
+		rate, _ := source["encoding_rate"].(float64)
+		if rate >= bestRate {
+			bestRate = rate
+			bestURL = src
+		}
+	}
+	return bestURL, bestURL != ""
+}
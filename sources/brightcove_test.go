@@ -0,0 +1,234 @@
+package sources
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adambraimbridge/video-mapper/internal/mp4"
+)
+
+func TestShouldSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy SplitPolicy
+		c      trackComposition
+		want   bool
+	}{
+		{"never splits even with both tracks known", SplitNever, trackComposition{hasVideo: true, hasAudio: true, videoCodec: "avc1", audioCodec: "mp4a"}, false},
+		{"always splits on video+audio tracks alone", SplitAlways, trackComposition{hasVideo: true, hasAudio: true}, true},
+		{"always doesn't split video-only", SplitAlways, trackComposition{hasVideo: true}, false},
+		{"always doesn't split audio-only", SplitAlways, trackComposition{hasAudio: true}, false},
+		{"auto requires both codecs known", SplitAuto, trackComposition{hasVideo: true, hasAudio: true}, false},
+		{"auto splits once both codecs are known", SplitAuto, trackComposition{hasVideo: true, hasAudio: true, videoCodec: "avc1", audioCodec: "mp4a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldSplit(tt.policy, tt.c); got != tt.want {
+				t.Errorf("shouldSplit(%v, %+v) = %v, want %v", tt.policy, tt.c, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectComposition(t *testing.T) {
+	tests := []struct {
+		name          string
+		brightcoveVid map[string]interface{}
+		info          *mp4.MediaInfo
+		fileName      string
+		want          trackComposition
+	}{
+		{
+			name:     "probed MP4 with separate video and audio tracks",
+			fileName: "clip.mp4",
+			info: &mp4.MediaInfo{Tracks: []mp4.Track{
+				{Type: "video", Codec: "avc1"},
+				{Type: "audio", Codec: "mp4a"},
+			}},
+			want: trackComposition{hasVideo: true, hasAudio: true, videoCodec: "avc1", audioCodec: "mp4a"},
+		},
+		{
+			name:     "probed MP4 with an audio track only",
+			fileName: "clip.mp4",
+			info:     &mp4.MediaInfo{Tracks: []mp4.Track{{Type: "audio", Codec: "mp4a"}}},
+			want:     trackComposition{hasAudio: true, audioCodec: "mp4a"},
+		},
+		{
+			name:          "unprobed VIDEO type field",
+			brightcoveVid: map[string]interface{}{"type": "VIDEO", "sources": []interface{}{map[string]interface{}{"container": "MP4"}}},
+			fileName:      "clip.mp4",
+			want:          trackComposition{hasVideo: true, videoCodec: "mp4"},
+		},
+		{
+			name:          "unprobed AUDIO type field",
+			brightcoveVid: map[string]interface{}{"type": "AUDIO", "sources": []interface{}{map[string]interface{}{"container": "MP3"}}},
+			fileName:      "clip.mp3",
+			want:          trackComposition{hasAudio: true, audioCodec: "mp3"},
+		},
+		{
+			name:     "extension fallback for a MOV with no type field",
+			fileName: "clip.mov",
+			want:     trackComposition{hasVideo: true, videoCodec: "mov"},
+		},
+		{
+			name:     "extension fallback for an MP3 with no type field",
+			fileName: "clip.mp3",
+			want:     trackComposition{hasAudio: true, audioCodec: "mp3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brightcoveVid := tt.brightcoveVid
+			if brightcoveVid == nil {
+				brightcoveVid = map[string]interface{}{}
+			}
+			got := detectComposition(brightcoveVid, tt.info, tt.fileName)
+			if got != tt.want {
+				t.Errorf("detectComposition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapTracksWithoutProbing(t *testing.T) {
+	tests := []struct {
+		name          string
+		brightcoveVid map[string]interface{}
+		wantMediaType string
+		wantIdentifer string
+	}{
+		{
+			name:          "MP4 video",
+			brightcoveVid: map[string]interface{}{"type": "VIDEO", "name": "clip.mp4"},
+			wantMediaType: "video/mp4",
+			wantIdentifer: "bc-1",
+		},
+		{
+			name:          "MOV video, no type field",
+			brightcoveVid: map[string]interface{}{"name": "clip.mov"},
+			wantMediaType: "video/mov",
+			wantIdentifer: "bc-1",
+		},
+		{
+			name:          "MP3 audio",
+			brightcoveVid: map[string]interface{}{"type": "AUDIO", "name": "clip.mp3"},
+			wantMediaType: "audio/mp3",
+			wantIdentifer: "bc-1",
+		},
+		{
+			name:          "audio-track-only MP4",
+			brightcoveVid: map[string]interface{}{"type": "AUDIO", "name": "clip.mp4", "sources": []interface{}{map[string]interface{}{"container": "MP4"}}},
+			wantMediaType: "audio/mp4",
+			wantIdentifer: "bc-1",
+		},
+		{
+			name:          "no fileName and no disambiguating type/container falls back to a real mime type, not a bare video/",
+			brightcoveVid: map[string]interface{}{"name": ""},
+			wantMediaType: "video/mp4",
+			wantIdentifer: "bc-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brightcoveVid := map[string]interface{}{
+				"uuid":       "uuid-1",
+				"id":         "bc-1",
+				"updated_at": "2020-01-01T00:00:00.000Z",
+			}
+			for k, v := range tt.brightcoveVid {
+				brightcoveVid[k] = v
+			}
+
+			b := NewBrightcove(nil, SplitAlways)
+			payloads, err := b.MapTracks(brightcoveVid, "req-1", "2020-01-01T00:00:00.000Z")
+			if err != nil {
+				t.Fatalf("MapTracks() error = %v", err)
+			}
+			if len(payloads) != 1 {
+				t.Fatalf("MapTracks() returned %d payloads, want 1 (no probe means composition can never carry both tracks)", len(payloads))
+			}
+			p := payloads[0]
+			if p.MediaType != tt.wantMediaType {
+				t.Errorf("MediaType = %q, want %q", p.MediaType, tt.wantMediaType)
+			}
+			if len(p.Identifiers) != 1 || p.Identifiers[0].IdentifierValue != tt.wantIdentifer {
+				t.Errorf("Identifiers = %+v, want a single identifier %q", p.Identifiers, tt.wantIdentifer)
+			}
+		})
+	}
+}
+
+// box encodes a single MP4 atom: a 4-byte size, a 4-byte type and its payload.
+func box(typ string, payload []byte) []byte {
+	b := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)))
+	copy(b[4:8], typ)
+	copy(b[8:], payload)
+	return b
+}
+
+// buildMinimalMP4 assembles just enough of a real MP4 box tree (ftyp, moov
+// with one video and one audio trak) for internal/mp4 to probe both tracks,
+// so MapTracks's splitting logic can be exercised end to end instead of only
+// through its own fallback (type-field / extension) heuristics.
+func buildMinimalMP4(t *testing.T) []byte {
+	t.Helper()
+
+	tkhd := make([]byte, 84) // version 0; width/height (16.16 fixed) in the last 8 bytes
+	binary.BigEndian.PutUint32(tkhd[76:80], 640<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 360<<16)
+
+	videoStsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, box("avc1", nil)...)
+	videoTrak := box("trak", append(box("tkhd", tkhd), box("mdia", box("minf", box("stbl", box("stsd", videoStsd))))...))
+
+	audioStsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, box("mp4a", make([]byte, 28))...)
+	audioTrak := box("trak", box("mdia", box("minf", box("stbl", box("stsd", audioStsd)))))
+
+	mvhd := make([]byte, 20) // version 0; timescale/duration at [12:16]/[16:20]
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], 5000)
+
+	moov := box("moov", append(box("mvhd", mvhd), append(videoTrak, audioTrak...)...))
+	ftyp := box("ftyp", []byte("isom"))
+	return append(ftyp, moov...)
+}
+
+func TestMapTracksSplitsOnProbedTracks(t *testing.T) {
+	body := buildMinimalMP4(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	brightcoveVid := map[string]interface{}{
+		"uuid":       "uuid-1",
+		"id":         "bc-1",
+		"name":       "clip.mp4",
+		"updated_at": "2020-01-01T00:00:00.000Z",
+		"sources": []interface{}{
+			map[string]interface{}{"container": "MP4", "src": server.URL, "encoding_rate": 1000000.0},
+		},
+	}
+
+	b := NewBrightcove(mp4.NewProber(time.Second), SplitAlways)
+	payloads, err := b.MapTracks(brightcoveVid, "req-1", "2020-01-01T00:00:00.000Z")
+	if err != nil {
+		t.Fatalf("MapTracks() error = %v", err)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("MapTracks() returned %d payloads, want 2 (split video/audio)", len(payloads))
+	}
+	video, audio := payloads[0], payloads[1]
+	if video.MediaType != "video/avc1" {
+		t.Errorf("video MediaType = %q, want %q", video.MediaType, "video/avc1")
+	}
+	if audio.MediaType != "audio/mp4a" {
+		t.Errorf("audio MediaType = %q, want %q", audio.MediaType, "audio/mp4a")
+	}
+	if video.Identifiers[0].IdentifierValue != "bc-1-video" || audio.Identifiers[0].IdentifierValue != "bc-1-audio" {
+		t.Errorf("unexpected identifiers: video=%+v audio=%+v", video.Identifiers, audio.Identifiers)
+	}
+}
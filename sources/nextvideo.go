@@ -0,0 +1,46 @@
+package sources
+
+import "fmt"
+
+const nextVideoOrigin = "http://cmdb.ft.com/systems/next-video-editor"
+const nextVideoAuthority = "http://api.ft.com/system/NEXT-VIDEO-EDITOR"
+
+// NextVideo maps native video JSON produced by the FT-native Next Video editor.
+// Unlike Brightcove, the uuid is the native id, so no id/uuid split is needed.
+type NextVideo struct{}
+
+// NewNextVideo returns a VideoSource for the Next Video origin.
+func NewNextVideo() *NextVideo {
+	return &NextVideo{}
+}
+
+func (n *NextVideo) Origin() string    { return nextVideoOrigin }
+func (n *NextVideo) Authority() string { return nextVideoAuthority }
+func (n *NextVideo) Name() string      { return "next-video" }
+
+func (n *NextVideo) Map(raw map[string]interface{}, publishReference, lastModified string) (Payload, error) {
+	uuid, _ := raw["uuid"].(string)
+	if uuid == "" {
+		return Payload{}, fmt.Errorf("uuid field of native next-video JSON is null. Skipping message.")
+	}
+	publishedDate, _ := raw["publishedDate"].(string)
+	if publishedDate == "" {
+		return Payload{}, fmt.Errorf("publishedDate field of native next-video JSON is null. Skipping message.")
+	}
+	mediaType, _ := raw["mediaType"].(string)
+	if mediaType == "" {
+		mediaType = videoMediaTypeBase + "mp4"
+	}
+	i := Identifier{
+		Authority:       nextVideoAuthority,
+		IdentifierValue: uuid,
+	}
+	return Payload{
+		UUID:             uuid,
+		Identifiers:      []Identifier{i},
+		PublishedDate:    publishedDate,
+		MediaType:        mediaType,
+		PublishReference: publishReference,
+		LastModified:     lastModified,
+	}, nil
+}
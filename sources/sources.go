@@ -0,0 +1,83 @@
+package sources
+
+import (
+	"fmt"
+
+	"github.com/adambraimbridge/video-mapper/internal/mp4"
+)
+
+// Identifier is a single authority/value pair attached to a mapped payload.
+type Identifier struct {
+	Authority       string `json:"authority"`
+	IdentifierValue string `json:"identifierValue"`
+}
+
+// Payload is the Content payload produced by mapping a native video message.
+type Payload struct {
+	UUID             string         `json:"uuid"`
+	Identifiers      []Identifier   `json:"identifiers"`
+	PublishedDate    string         `json:"publishedDate"`
+	MediaType        string         `json:"mediaType"`
+	PublishReference string         `json:"publishReference"`
+	LastModified     string         `json:"lastModified"`
+	MediaInfo        *mp4.MediaInfo `json:"mediaInfo,omitempty"`
+}
+
+// VideoSource maps a native video message from a single origin into a Payload.
+// Implementations are registered with a Registry and looked up either by the
+// Kafka Origin-System-Id header or by the ?source= query param on /map.
+type VideoSource interface {
+	// Origin is the Origin-System-Id (CMDB URI) this source consumes from.
+	Origin() string
+	// Authority is the identifier authority used for this source's native id.
+	Authority() string
+	// Name is the short, URL-friendly name used to select this source via ?source=.
+	Name() string
+	// Map converts a decoded native video JSON message into a Content payload.
+	Map(raw map[string]interface{}, publishRef, lastModified string) (Payload, error)
+}
+
+// TrackMapper is implemented by sources that can publish a video's tracks as
+// more than one payload (e.g. splitting audio and video into independent
+// publications). Callers that need the full set should type-assert for it
+// instead of relying on VideoSource.Map, which always returns a single payload.
+type TrackMapper interface {
+	MapTracks(raw map[string]interface{}, publishRef, lastModified string) ([]Payload, error)
+}
+
+// Registry looks up a VideoSource either by origin or by short name.
+type Registry struct {
+	byOrigin map[string]VideoSource
+	byName   map[string]VideoSource
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byOrigin: make(map[string]VideoSource),
+		byName:   make(map[string]VideoSource),
+	}
+}
+
+// Register adds a VideoSource to the registry, indexed by both its origin and its name.
+func (r *Registry) Register(s VideoSource) {
+	r.byOrigin[s.Origin()] = s
+	r.byName[s.Name()] = s
+}
+
+// ByOrigin looks up a VideoSource by its Origin-System-Id header value.
+func (r *Registry) ByOrigin(origin string) (VideoSource, bool) {
+	s, found := r.byOrigin[origin]
+	return s, found
+}
+
+// ByName looks up a VideoSource by its short name, as used in ?source=.
+func (r *Registry) ByName(name string) (VideoSource, bool) {
+	s, found := r.byName[name]
+	return s, found
+}
+
+// ErrUnknownSource is returned when a request can't be attributed to any registered VideoSource.
+func ErrUnknownSource(originOrName string) error {
+	return fmt.Errorf("no video source registered for [%v]", originOrName)
+}
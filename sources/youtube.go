@@ -0,0 +1,45 @@
+package sources
+
+import "fmt"
+
+const youTubeOrigin = "http://cmdb.ft.com/systems/youtube"
+const youTubeAuthority = "http://api.ft.com/system/YOUTUBE"
+
+// YouTube maps native video JSON describing a YouTube video, keyed by videoId.
+type YouTube struct{}
+
+// NewYouTube returns a generic VideoSource for the YouTube origin.
+func NewYouTube() *YouTube {
+	return &YouTube{}
+}
+
+func (y *YouTube) Origin() string    { return youTubeOrigin }
+func (y *YouTube) Authority() string { return youTubeAuthority }
+func (y *YouTube) Name() string      { return "youtube" }
+
+func (y *YouTube) Map(raw map[string]interface{}, publishReference, lastModified string) (Payload, error) {
+	uuid, _ := raw["uuid"].(string)
+	if uuid == "" {
+		return Payload{}, fmt.Errorf("uuid field of native youtube video JSON is null. Skipping message.")
+	}
+	videoID, _ := raw["videoId"].(string)
+	if videoID == "" {
+		return Payload{}, fmt.Errorf("videoId field of native youtube video JSON is null. Skipping message.")
+	}
+	publishedDate, _ := raw["publishedAt"].(string)
+	if publishedDate == "" {
+		return Payload{}, fmt.Errorf("publishedAt field of native youtube video JSON is null. Skipping message.")
+	}
+	i := Identifier{
+		Authority:       youTubeAuthority,
+		IdentifierValue: videoID,
+	}
+	return Payload{
+		UUID:             uuid,
+		Identifiers:      []Identifier{i},
+		PublishedDate:    publishedDate,
+		MediaType:        videoMediaTypeBase + "youtube",
+		PublishReference: publishReference,
+		LastModified:     lastModified,
+	}, nil
+}
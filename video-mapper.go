@@ -1,52 +1,39 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"errors"
 	"fmt"
 	"github.com/Financial-Times/message-queue-go-producer/producer"
 	"github.com/Financial-Times/message-queue-gonsumer/consumer"
+	"github.com/adambraimbridge/video-mapper/internal/manifest"
+	"github.com/adambraimbridge/video-mapper/internal/mp4"
+	"github.com/adambraimbridge/video-mapper/listeners"
+	"github.com/adambraimbridge/video-mapper/sources"
 	"github.com/gorilla/mux"
 	"github.com/jawher/mow.cli"
-	"strings"
 )
 
 const videoContentUriBase = "http://video-mapper-iw-uk-p.svc.ft.com/video/model/"
-const brigthcoveAuthority = "http://api.ft.com/system/BRIGHTCOVE"
-const viodeMediaTypeBase = "video/"
-const brightcoveOrigin = "http://cmdb.ft.com/systems/brightcove"
-
-type publicationEvent struct {
-	ContentUri   string `json:"contentUri"`
-	Payload      string `json:"payload"`
-	LastModified string `json:"lastModified"`
-}
-
-type identifier struct {
-	Authority       string `json:"authority"`
-	IdentifierValue string `json:"identifierValue"`
-}
-
-type payload struct {
-	UUID             string       `json:"uuid"`
-	Identifiers      []identifier `json:"identifiers"`
-	PublishedDate    string       `json:"publishedDate"`
-	MediaType        string       `json:"mediaType"`
-	PublishReference string       `json:"publishReference"`
-	LastModified     string       `json:"lastModified"`
-}
+const audioContentUriBase = "http://video-mapper-iw-uk-p.svc.ft.com/audio/model/"
+const videoManifestContentUriBase = "http://video-mapper-iw-uk-p.svc.ft.com/video/manifest/"
+const dashManifestMediaType = "application/dash+xml"
 
 type videoMapper struct {
-	messageConsumer *consumer.Consumer
-	messageProducer *producer.MessageProducer
+	messageConsumer  *consumer.Consumer
+	dispatcher       *listeners.Dispatcher
+	sources          *sources.Registry
+	emitDashManifest bool
 }
 
 type errorString struct {
@@ -105,8 +92,66 @@ func main() {
 		Desc:   "Authorization key to access the queue.",
 		EnvVar: "Q_AUTHORIZATION",
 	})
+	enabledSources := app.Strings(cli.StringsOpt{
+		Name:   "sources",
+		Value:  []string{"brightcove"},
+		Desc:   "Video sources to enable, by name (brightcove, next-video, youtube).",
+		EnvVar: "SOURCES",
+	})
+	originMappings := app.Strings(cli.StringsOpt{
+		Name:   "origin-map",
+		Value:  []string{},
+		Desc:   "Extra origin=source mappings, e.g. http://cmdb.ft.com/systems/some-origin=youtube. Built-in sources already map their own origin.",
+		EnvVar: "ORIGIN_MAP",
+	})
+	probeMedia := app.Bool(cli.BoolOpt{
+		Name:   "probe-media",
+		Value:  false,
+		Desc:   "Probe the highest-quality Brightcove rendition's MP4 boxes to enrich the payload with real media metadata.",
+		EnvVar: "PROBE_MEDIA",
+	})
+	probeTimeout := app.Int(cli.IntOpt{
+		Name:   "probe-timeout-ms",
+		Value:  2000,
+		Desc:   "Timeout, in milliseconds, for a single media probe. Ignored unless --probe-media is set.",
+		EnvVar: "PROBE_TIMEOUT_MS",
+	})
+	emitDashManifest := app.Bool(cli.BoolOpt{
+		Name:   "emit-dash-manifest",
+		Value:  false,
+		Desc:   "Also emit a companion DASH MPD manifest publication event for Brightcove videos.",
+		EnvVar: "EMIT_DASH_MANIFEST",
+	})
+	enabledListeners := app.Strings(cli.StringsOpt{
+		Name:   "listeners",
+		Value:  []string{"kafka"},
+		Desc:   "Event listeners to enable, by name (kafka, file). gRPC has no built-in flag since it needs a generated client; wire listeners.NewGRPCListener up in code if you need it. Every listener's OnCommit fires once per consumed message, not once per Kafka batch/offset-commit - a listener that only needs to flush periodically (e.g. the file listener's fsync) will be called more often than that name suggests.",
+		EnvVar: "LISTENERS",
+	})
+	fileListenerDir := app.String(cli.StringOpt{
+		Name:   "file-listener-dir",
+		Value:  ".",
+		Desc:   "Directory the file listener writes its rotating frame files to. Ignored unless the file listener is enabled.",
+		EnvVar: "FILE_LISTENER_DIR",
+	})
+	haltOnListenerError := app.Bool(cli.BoolOpt{
+		Name:   "halt-on-listener-error",
+		Value:  true,
+		Desc:   "If true, a listener error stops the app (and so the offset commit) instead of being logged and skipped.",
+		EnvVar: "HALT_ON_LISTENER_ERROR",
+	})
+	splitAV := app.String(cli.StringOpt{
+		Name:   "split-av",
+		Value:  string(sources.SplitNever),
+		Desc:   "When to split a Brightcove asset with independent video and audio tracks into two payloads: never, auto, always.",
+		EnvVar: "SPLIT_AV",
+	})
 	app.Action = func() {
 		initLogs(os.Stdout, os.Stdout, os.Stderr)
+		sourceRegistry, err := buildSourceRegistry(*enabledSources, *originMappings, *probeMedia, time.Duration(*probeTimeout)*time.Millisecond, sources.SplitPolicy(*splitAV))
+		if err != nil {
+			errorLogger.Panicf("Couldn't build video source registry: %+v\n", err)
+		}
 		consumerConfig := consumer.QueueConfig{
 			Addrs:                *addresses,
 			Group:                *group,
@@ -122,11 +167,13 @@ func main() {
 			Authorization: *authorization,
 		}
 		messageProducer := producer.NewMessageProducer(producerConfig)
-		headers := make(map[string]string)
-		messageProducer.SendMessage("", producer.Message{Headers: headers, Body: ""})
+		dispatcher, err := buildDispatcher(*enabledListeners, *haltOnListenerError, messageProducer, *fileListenerDir)
+		if err != nil {
+			errorLogger.Panicf("Couldn't build event listener dispatcher: %+v\n", err)
+		}
 		var v videoMapper
 		messageConsumer := consumer.NewConsumer(consumerConfig, v.consume, http.Client{})
-		v = videoMapper{&messageConsumer, &messageProducer}
+		v = videoMapper{&messageConsumer, dispatcher, sourceRegistry, *emitDashManifest}
 		hc := &healthcheck{client: http.Client{}, consumerConf: consumerConfig}
 		go v.listen(hc)
 		v.consumeUntilSigterm()
@@ -167,10 +214,72 @@ func (v videoMapper) consumeUntilSigterm() {
 	consumerWaitGroup.Wait()
 }
 
+// buildSourceRegistry registers the enabled built-in VideoSources and applies any extra
+// origin=source mappings on top, so an operator can point a non-default origin at a
+// shared adapter (e.g. a second Brightcove account publishing under its own origin id).
+func buildSourceRegistry(enabledSources, originMappings []string, probeMedia bool, probeTimeout time.Duration, splitAV sources.SplitPolicy) (*sources.Registry, error) {
+	var prober *mp4.Prober
+	if probeMedia {
+		prober = mp4.NewProber(probeTimeout)
+	}
+	available := map[string]sources.VideoSource{
+		"brightcove": sources.NewBrightcove(prober, splitAV),
+		"next-video": sources.NewNextVideo(),
+		"youtube":    sources.NewYouTube(),
+	}
+	registry := sources.NewRegistry()
+	for _, name := range enabledSources {
+		s, found := available[name]
+		if !found {
+			return nil, fmt.Errorf("unknown video source [%v]", name)
+		}
+		registry.Register(s)
+	}
+	for _, mapping := range originMappings {
+		parts := strings.SplitN(mapping, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("origin-map entry [%v] is not of the form origin=source", mapping)
+		}
+		origin, name := parts[0], parts[1]
+		s, found := registry.ByName(name)
+		if !found {
+			return nil, fmt.Errorf("origin-map entry [%v] refers to source [%v] which isn't enabled", mapping, name)
+		}
+		registry.Register(originAlias{VideoSource: s, origin: origin})
+	}
+	return registry, nil
+}
+
+// buildDispatcher registers the built-in EventListeners named in enabledListeners,
+// in that order. The Kafka listener is only dispatched to when "kafka" is among them.
+func buildDispatcher(enabledListeners []string, haltOnError bool, messageProducer producer.MessageProducer, fileListenerDir string) (*listeners.Dispatcher, error) {
+	available := map[string]listeners.EventListener{
+		"kafka": listeners.NewKafkaListener(messageProducer),
+		"file":  listeners.NewFileListener(fileListenerDir),
+	}
+	var enabled []listeners.EventListener
+	for _, name := range enabledListeners {
+		l, found := available[name]
+		if !found {
+			return nil, fmt.Errorf("unknown event listener [%v]", name)
+		}
+		enabled = append(enabled, l)
+	}
+	return listeners.NewDispatcher(haltOnError, enabled...), nil
+}
+
+// originAlias lets a single VideoSource also be looked up under an additional origin.
+type originAlias struct {
+	sources.VideoSource
+	origin string
+}
+
+func (a originAlias) Origin() string { return a.origin }
+
 func (v videoMapper) mapHandler(w http.ResponseWriter, r *http.Request) {
-	var brightcoveVideo map[string]interface{}
+	var rawVideo map[string]interface{}
 
-	err := json.NewDecoder(r.Body).Decode(&brightcoveVideo)
+	err := json.NewDecoder(r.Body).Decode(&rawVideo)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -185,31 +294,63 @@ func (v videoMapper) mapHandler(w http.ResponseWriter, r *http.Request) {
 		warnLogger.Printf("Message-Timestamp not found in kafka message headers. Skipping message.")
 		return
 	}
-	mappedVideoBytes, err := v.mapBrightcoveVideo(brightcoveVideo, publishReference, lastModified)
+	sourceName := r.URL.Query().Get("source")
+	if sourceName == "" {
+		// Preserve the behaviour of the old, source-less /map endpoint: its
+		// only caller was Brightcove.
+		sourceName = "brightcove"
+	}
+	source, found := v.sources.ByName(sourceName)
+	if !found {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	mappedEvents, err := v.mapVideo(source, rawVideo, publishReference, lastModified)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	w.Write(mappedVideoBytes)
+	for _, event := range mappedEvents {
+		marshalledEvent, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		w.Write(marshalledEvent)
+		w.Write([]byte("\n"))
+	}
 }
 
 func (v videoMapper) consume(m consumer.Message) {
-	if m.Headers["Origin-System-Id"] != brightcoveOrigin {
+	source, found := v.sources.ByOrigin(m.Headers["Origin-System-Id"])
+	if !found {
 		return
 	}
-	marshalledEvent, err := v.mapMessage(m)
+	mappedEvents, err := v.mapMessage(source, m)
 	if err != nil {
 		warnLogger.Printf("Mapping error: [%v]", err.Error())
 		return
 	}
-	infoLogger.Printf("Sending %v", marshalledEvent)
-	//(*v.messageProducer).SendMessage(id, producer.Message{Headers: m.Headers, Body: string(cocoVideoS)})
+	ctx := context.Background()
+	for _, event := range mappedEvents {
+		if err := v.dispatcher.DispatchMappedEvent(ctx, event, m.Headers); err != nil {
+			if v.dispatcher.HaltOnError() {
+				errorLogger.Panicf("Listener failed to handle event %v: %+v\n", event, err)
+			}
+			warnLogger.Printf("Listener failed to handle event %v: %+v\n", event, err)
+		}
+	}
+	if err := v.dispatcher.Commit(ctx); err != nil {
+		if v.dispatcher.HaltOnError() {
+			errorLogger.Panicf("Listener failed to handle commit: %+v\n", err)
+		}
+		warnLogger.Printf("Listener failed to handle commit: %+v\n", err)
+	}
 }
 
-func (v videoMapper) mapMessage(m consumer.Message) ([]byte, error) {
-	var brightcoveVideo map[string]interface{}
-	if err := json.Unmarshal([]byte(m.Body), &brightcoveVideo); err != nil {
-		return nil, errors.New(fmt.Sprintf("Video JSON from Brightcove couldn't be unmarshalled. Skipping invalid JSON: %v", m.Body))
+func (v videoMapper) mapMessage(source sources.VideoSource, m consumer.Message) ([]listeners.Event, error) {
+	var rawVideo map[string]interface{}
+	if err := json.Unmarshal([]byte(m.Body), &rawVideo); err != nil {
+		return nil, errors.New(fmt.Sprintf("Video JSON from %v couldn't be unmarshalled. Skipping invalid JSON: %v", source.Name(), m.Body))
 	}
 	publishReference := m.Headers["X-Request-Id"]
 	if publishReference == "" {
@@ -219,56 +360,79 @@ func (v videoMapper) mapMessage(m consumer.Message) ([]byte, error) {
 	if lastModified == "" {
 		return nil, errors.New("Message-Timestamp not found in kafka message headers. Skipping message.")
 	}
-	return v.mapBrightcoveVideo(brightcoveVideo, publishReference, lastModified)
+	return v.mapVideo(source, rawVideo, publishReference, lastModified)
 }
 
-func (v videoMapper) mapBrightcoveVideo(brightcoveVideo map[string]interface{}, publishReference, lastModified string) ([]byte, error) {
-	uuid := brightcoveVideo["uuid"].(string)
-	contentUri := videoContentUriBase + uuid
-	if uuid == "" {
-		return nil, errors.New(fmt.Sprintf("uuid field of native brightcove video JSON is null. Skipping message."))
-	}
-	id := brightcoveVideo["id"].(string)
-	if id == "" {
-		return nil, errors.New(fmt.Sprintf("id field of native brightcove video JSON is null. Skipping message."))
-	}
-	publishedDate := brightcoveVideo["updated_at"].(string)
-	if publishedDate == "" {
-		return nil, errors.New(fmt.Sprintf("updated_at field of native brightcove video JSON is null. Skipping message."))
+// mapVideo maps the raw video JSON to one Content publication event per payload
+// (more than one when the source splits independent audio and video tracks)
+// and, when --emit-dash-manifest is set and the source is Brightcove, a
+// further publication event carrying a companion DASH MPD manifest.
+func (v videoMapper) mapVideo(source sources.VideoSource, rawVideo map[string]interface{}, publishReference, lastModified string) ([]listeners.Event, error) {
+	payloads, err := mapPayloads(source, rawVideo, publishReference, lastModified)
+	if err != nil {
+		return nil, err
 	}
-	fileName := brightcoveVideo["name"].(string)
-	if fileName == "" {
-		warnLogger.Printf("filename field of native brightcove video JSON is null, type will be video/.")
+
+	var events []listeners.Event
+	for _, p := range payloads {
+		marshalledPayload, err := json.Marshal(p)
+		if err != nil {
+			warnLogger.Printf("Couldn't marshall payload %v, skipping message.", p)
+			return nil, err
+		}
+		events = append(events, listeners.Event{
+			ContentUri:   contentUriFor(p),
+			Payload:      string(marshalledPayload),
+			MediaType:    p.MediaType,
+			LastModified: lastModified,
+		})
 	}
-	extension := strings.TrimPrefix(filepath.Ext(fileName), ".")
-	mediaType := viodeMediaTypeBase + extension
-	i := identifier{
-		Authority:       brigthcoveAuthority,
-		IdentifierValue: id,
+
+	if v.emitDashManifest && source.Name() == "brightcove" {
+		if manifestEvent, err := v.mapDashManifest(rawVideo, payloads[0], lastModified); err != nil {
+			warnLogger.Printf("Couldn't build DASH manifest for video [%v]: %v", payloads[0].UUID, err)
+		} else {
+			events = append(events, manifestEvent)
+		}
 	}
-	p := payload{
-		UUID:             uuid,
-		Identifiers:      []identifier{i},
-		PublishedDate:    publishedDate,
-		MediaType:        mediaType,
-		PublishReference: publishReference,
-		LastModified:     lastModified,
+
+	return events, nil
+}
+
+// mapPayloads calls MapTracks on source when it supports splitting into
+// several payloads, falling back to its single-payload Map otherwise.
+func mapPayloads(source sources.VideoSource, rawVideo map[string]interface{}, publishReference, lastModified string) ([]sources.Payload, error) {
+	if trackMapper, ok := source.(sources.TrackMapper); ok {
+		return trackMapper.MapTracks(rawVideo, publishReference, lastModified)
 	}
-	marshalledPayload, err := json.Marshal(p)
+	p, err := source.Map(rawVideo, publishReference, lastModified)
 	if err != nil {
-		warnLogger.Printf("Couldn't marshall payload %v, skipping message.", p)
 		return nil, err
 	}
-	//fmt.Println(strconv.Quote(ss))
-	e := publicationEvent{
-		ContentUri:   contentUri,
-		Payload:      string(marshalledPayload),
-		LastModified: lastModified,
+	return []sources.Payload{p}, nil
+}
+
+// contentUriFor picks the video or audio content URI base according to p's MediaType.
+func contentUriFor(p sources.Payload) string {
+	if strings.HasPrefix(p.MediaType, audioMediaTypePrefix) {
+		return audioContentUriBase + p.UUID
 	}
-	marshalledEvent, err := json.Marshal(e)
+	return videoContentUriBase + p.UUID
+}
+
+const audioMediaTypePrefix = "audio/"
+
+func (v videoMapper) mapDashManifest(rawVideo map[string]interface{}, p sources.Payload, lastModified string) (listeners.Event, error) {
+	renditions := manifest.RenditionsFromBrightcove(rawVideo)
+	manifestBytes, err := manifest.NewMPDBuilder().Build(p.UUID, renditions, p.MediaInfo)
 	if err != nil {
-		warnLogger.Printf("Couldn't marshall event %v, skipping message.", e)
-		return nil, err
+		return listeners.Event{}, err
 	}
-	return marshalledEvent, nil
+	infoLogger.Printf("Built %v manifest for video [%v]", dashManifestMediaType, p.UUID)
+	return listeners.Event{
+		ContentUri:   videoManifestContentUriBase + p.UUID,
+		Payload:      string(manifestBytes),
+		MediaType:    dashManifestMediaType,
+		LastModified: lastModified,
+	}, nil
 }